@@ -0,0 +1,245 @@
+// Package audit implements an append-only, tamper-evident audit log for
+// PII Guard's /anonymize and /analyze calls. Entries never store the
+// original text, only a salted hash of it, so the log itself cannot leak
+// PII even if it is exfiltrated.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is one audit record. Hash is computed over the entry (with Hash
+// cleared) chained onto PrevHash, so Store.Verify can detect truncation
+// or tampering anywhere in the file.
+type Entry struct {
+	Index         int64     `json:"index"`
+	Timestamp     time.Time `json:"timestamp"`
+	Endpoint      string    `json:"endpoint"`
+	TextHash      string    `json:"text_hash"`
+	EntityTypes   []string  `json:"entity_types"`
+	EntityCount   int       `json:"entity_count"`
+	CallerIP      string    `json:"caller_ip"`
+	ConfigVersion int       `json:"config_version"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+}
+
+// genesisHash is PrevHash for the first entry in the chain: 64 hex chars,
+// the same shape as a real SHA-256 digest (even though it's never itself
+// the output of one), so it doesn't mislead anything inspecting the
+// chain format.
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Store is an append-only JSONL audit log with a SHA-256 hash chain.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	salt      []byte
+	nextIndex int64
+	lastHash  string
+}
+
+// NewStore opens (or creates) the JSONL log at path and replays it to
+// recover the chain head. salt is mixed into TextHash so the log cannot
+// be used to brute-force the original text.
+func NewStore(path string, salt []byte) (*Store, error) {
+	s := &Store{path: path, salt: salt, lastHash: genesisHash}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("corrupt audit log at line %d: %w", s.nextIndex+1, err)
+		}
+		s.lastHash = e.Hash
+		s.nextIndex = e.Index + 1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+
+	return s, nil
+}
+
+// HashText returns a salted, hex-encoded SHA-256 hash of text for storage
+// in TextHash. The original text is never written to disk.
+func (s *Store) HashText(text string) string {
+	mac := hmac.New(sha256.New, s.salt)
+	mac.Write([]byte(text))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Append writes a new entry to the log, filling in Index, PrevHash and
+// Hash, and returns the stored entry.
+func (s *Store) Append(e Entry) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.Index = s.nextIndex
+	e.PrevHash = s.lastHash
+	e.Hash = ""
+	e.Hash = chainHash(s.lastHash, e)
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Entry{}, fmt.Errorf("open audit log for append: %w", err)
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, fmt.Errorf("marshal audit entry: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return Entry{}, fmt.Errorf("write audit entry: %w", err)
+	}
+
+	s.lastHash = e.Hash
+	s.nextIndex++
+	return e, nil
+}
+
+// chainHash computes h_n = SHA256(h_{n-1} || canonical_json(entry)) with
+// entry.Hash cleared beforehand by the caller.
+func chainHash(prevHash string, e Entry) string {
+	canonical, _ := json.Marshal(e)
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Filter narrows List to entries matching specific fields. Zero-valued
+// fields are not filtered on, except Since/Until which are compared only
+// when non-zero.
+type Filter struct {
+	Endpoint   string
+	EntityType string
+	CallerIP   string
+	Since      time.Time
+	Until      time.Time
+}
+
+// matches reports whether e satisfies every non-zero field of f.
+func (f Filter) matches(e Entry) bool {
+	if f.Endpoint != "" && e.Endpoint != f.Endpoint {
+		return false
+	}
+	if f.CallerIP != "" && e.CallerIP != f.CallerIP {
+		return false
+	}
+	if f.EntityType != "" {
+		found := false
+		for _, t := range e.EntityTypes {
+			if t == f.EntityType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// List returns entries matching filter in [offset, offset+limit), in
+// index order, along with the total count of entries matching filter
+// (before pagination).
+func (s *Store) List(filter Filter, offset, limit int) ([]Entry, int, error) {
+	all, err := s.readAll()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matched := make([]Entry, 0, len(all))
+	for _, e := range all {
+		if filter.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	total := len(matched)
+	if offset >= total {
+		return []Entry{}, total, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > total {
+		end = total
+	}
+	return matched[offset:end], total, nil
+}
+
+// Verify walks the chain from genesis and returns ok=true if every hash
+// links correctly. If a link is broken, ok is false and brokenIndex is
+// the index of the first entry whose Hash doesn't match.
+func (s *Store) Verify() (ok bool, brokenIndex int64, err error) {
+	all, err := s.readAll()
+	if err != nil {
+		return false, 0, err
+	}
+
+	prev := genesisHash
+	for _, e := range all {
+		if e.PrevHash != prev {
+			return false, e.Index, nil
+		}
+		want := e.Hash
+		e.Hash = ""
+		if chainHash(prev, e) != want {
+			return false, e.Index, nil
+		}
+		prev = want
+	}
+	return true, -1, nil
+}
+
+func (s *Store) readAll() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("corrupt audit log: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	return entries, nil
+}
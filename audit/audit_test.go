@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	store, err := NewStore(path, []byte("salt"))
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := store.Append(Entry{Timestamp: time.Now().UTC(), Endpoint: "/anonymize"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	if ok, _, err := store.Verify(); err != nil || !ok {
+		t.Fatalf("Verify() on an untampered log = (%v, err=%v), want ok", ok, err)
+	}
+
+	if ok, _, err := store.List(Filter{}, 0, 100); err != nil || len(ok) != 3 {
+		t.Fatalf("List() = (%d entries, err=%v), want 3 entries", len(ok), err)
+	}
+
+	// Rewrite the second entry's recorded endpoint, after the
+	// hash chain was already computed over the original content.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines in the audit log, got %d", len(lines))
+	}
+	tampered := bytes.Replace(lines[1], []byte("/anonymize"), []byte("/analyzeX"), 1)
+	if bytes.Equal(tampered, lines[1]) {
+		t.Fatal("test setup: tamper replace had no effect")
+	}
+	lines[1] = tampered
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	reopened, err := NewStore(path, []byte("salt"))
+	if err != nil {
+		t.Fatalf("NewStore after tamper: %v", err)
+	}
+	ok, brokenIndex, err := reopened.Verify()
+	if err != nil {
+		t.Fatalf("Verify after tamper: %v", err)
+	}
+	if ok {
+		t.Fatal("Verify should detect the tampered entry")
+	}
+	if brokenIndex != 1 {
+		t.Fatalf("brokenIndex = %d, want 1", brokenIndex)
+	}
+}
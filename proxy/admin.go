@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// newAdminMux builds the admin-only mux served on a separate listener:
+// Prometheus metrics, net/http/pprof debug handlers, and the audit log
+// and sidecar log endpoints. All of these can leak sensitive information
+// (profiling can leak memory contents, the audit trail and raw sidecar
+// stderr are exactly the kind of data this proxy exists to protect) so
+// the whole mux is wrapped in bearerAuth.
+func newAdminMux(metrics *Metrics, handler *ProxyHandler, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.WriteText(w)
+	})
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("GET /audit", handler.HandleAuditList)
+	mux.HandleFunc("GET /audit/verify", handler.HandleAuditVerify)
+	mux.HandleFunc("GET /sidecar/logs", handler.HandleSidecarLogs)
+
+	return bearerAuth(token, mux)
+}
+
+// bearerAuth requires `Authorization: Bearer <token>` on every request.
+func bearerAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
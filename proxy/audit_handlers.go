@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Mr-Neutr0n/pii-guard/audit"
+)
+
+// HandleAuditList returns a page of audit entries. Query params:
+// offset (default 0), limit (default 100, max 1000), endpoint,
+// entity_type, caller_ip, since and until (RFC3339 timestamps) to filter
+// the matched entries before pagination is applied.
+func (h *ProxyHandler) HandleAuditList(w http.ResponseWriter, r *http.Request) {
+	if h.audit == nil {
+		http.Error(w, `{"error":"audit log not configured"}`, http.StatusNotFound)
+		return
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	entries, total, err := h.audit.List(filter, offset, limit)
+	if err != nil {
+		http.Error(w, `{"error":"failed to read audit log"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]any{"entries": entries, "total": total, "offset": offset, "limit": limit})
+}
+
+// parseAuditFilter builds an audit.Filter from GET /audit query params.
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	filter := audit.Filter{
+		Endpoint:   q.Get("endpoint"),
+		EntityType: q.Get("entity_type"),
+		CallerIP:   q.Get("caller_ip"),
+	}
+
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return audit.Filter{}, fmt.Errorf("since must be RFC3339: %w", err)
+		}
+		filter.Since = since
+	}
+	if v := q.Get("until"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return audit.Filter{}, fmt.Errorf("until must be RFC3339: %w", err)
+		}
+		filter.Until = until
+	}
+
+	return filter, nil
+}
+
+// HandleAuditVerify walks the audit log's hash chain and reports whether
+// it is intact. If not, it returns the index of the first broken link.
+func (h *ProxyHandler) HandleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if h.audit == nil {
+		http.Error(w, `{"error":"audit log not configured"}`, http.StatusNotFound)
+		return
+	}
+
+	ok, brokenIndex, err := h.audit.Verify()
+	if err != nil {
+		http.Error(w, `{"error":"failed to verify audit log"}`, http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]any{"ok": ok}
+	if !ok {
+		resp["broken_index"] = brokenIndex
+	}
+	writeJSON(w, resp)
+}
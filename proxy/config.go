@@ -2,10 +2,13 @@ package main
 
 import "sync"
 
-// Config manages which PII entity types are active for detection.
+// Config manages which PII entity types are active for detection and
+// which operator (replace/mask/hash/redact/encrypt) applies to each.
 type Config struct {
-	mu       sync.RWMutex
-	Entities map[string]bool `json:"entities"`
+	mu        sync.RWMutex
+	Entities  map[string]bool           `json:"entities"`
+	Operators map[string]EntityOperator `json:"operators"`
+	version   int
 }
 
 var defaultEntities = map[string]bool{
@@ -28,7 +31,7 @@ func NewConfig() *Config {
 	for k, v := range defaultEntities {
 		entities[k] = v
 	}
-	return &Config{Entities: entities}
+	return &Config{Entities: entities, Operators: make(map[string]EntityOperator)}
 }
 
 func (c *Config) GetEntities() map[string]bool {
@@ -45,6 +48,46 @@ func (c *Config) SetEntity(entity string, enabled bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.Entities[entity] = enabled
+	c.version++
+}
+
+// Version returns a monotonically increasing counter bumped on every
+// config mutation, so callers (e.g. the audit log) can record which
+// config was in effect for a given request.
+func (c *Config) Version() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version
+}
+
+// GetOperator returns the operator configured for entity, defaulting to
+// OperatorReplace if none was set.
+func (c *Config) GetOperator(entity string) EntityOperator {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if op, ok := c.Operators[entity]; ok {
+		return op
+	}
+	return EntityOperator{Type: OperatorReplace}
+}
+
+// SetOperator configures the operator used for entity.
+func (c *Config) SetOperator(entity string, op EntityOperator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Operators[entity] = op
+	c.version++
+}
+
+// GetOperators returns a copy of all explicitly configured operators.
+func (c *Config) GetOperators() map[string]EntityOperator {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cp := make(map[string]EntityOperator, len(c.Operators))
+	for k, v := range c.Operators {
+		cp[k] = v
+	}
+	return cp
 }
 
 func (c *Config) EnabledEntities() []string {
@@ -2,18 +2,35 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Mr-Neutr0n/pii-guard/audit"
 )
 
 // ProxyHandler handles HTTP requests for the PII Guard proxy.
 type ProxyHandler struct {
-	presidio *PresidioClient
-	config   *Config
+	presidio  *PresidioClient
+	config    *Config
+	audit     *audit.Store
+	operators *OperatorEngine
+	metrics   *Metrics
+	sidecar   *SidecarManager
 }
 
 type AnonymizeRequest struct {
 	Text string `json:"text"`
+	// Operators optionally overrides the configured operator for specific
+	// entity types, for this call only.
+	Operators map[string]EntityOperator `json:"operators,omitempty"`
+}
+
+type DeanonymizeRequest struct {
+	Text     string            `json:"text"`
+	Manifest map[string]string `json:"manifest"`
 }
 
 // writeJSON writes v as JSON without HTML escaping (so <PERSON> stays readable).
@@ -37,15 +54,59 @@ func (h *ProxyHandler) HandleAnonymize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.presidio.Anonymize(req.Text)
+	if !h.waitForSidecar(w) {
+		return
+	}
+
+	entities, err := h.presidio.Analyze(req.Text)
 	if err != nil {
-		log.Printf("Presidio anonymize error: %v", err)
+		log.Printf("Presidio analyze error: %v", err)
 		http.Error(w, `{"error":"presidio unavailable"}`, http.StatusBadGateway)
 		return
 	}
 
-	log.Printf("Anonymized: %d entities found", result.Count)
-	writeJSON(w, result)
+	text, manifest, err := h.operators.Apply(req.Text, entities, func(entityType string) EntityOperator {
+		if op, ok := req.Operators[entityType]; ok {
+			return op
+		}
+		return h.config.GetOperator(entityType)
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Anonymized: %d entities found", len(entities))
+	h.logAudit("/anonymize", req.Text, entities, r)
+
+	resp := map[string]any{"text": text, "entities": entities, "count": len(entities)}
+	if len(manifest) > 0 {
+		resp["manifest"] = manifest
+	}
+	writeJSON(w, resp)
+}
+
+// HandleDeanonymize restores text produced by the encrypt operator,
+// given the manifest of placeholder -> encrypted payload returned
+// alongside it by /anonymize.
+func (h *ProxyHandler) HandleDeanonymize(w http.ResponseWriter, r *http.Request) {
+	var req DeanonymizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	text := req.Text
+	for token, payload := range req.Manifest {
+		original, err := h.operators.Decrypt(payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		text = strings.ReplaceAll(text, token, original)
+	}
+
+	writeJSON(w, map[string]string{"text": text})
 }
 
 // HandleAnalyze receives text, returns detected PII entities.
@@ -61,6 +122,10 @@ func (h *ProxyHandler) HandleAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.waitForSidecar(w) {
+		return
+	}
+
 	entities, err := h.presidio.Analyze(req.Text)
 	if err != nil {
 		log.Printf("Presidio analyze error: %v", err)
@@ -68,13 +133,56 @@ func (h *ProxyHandler) HandleAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.logAudit("/analyze", req.Text, entities, r)
 	writeJSON(w, map[string]any{"entities": entities, "count": len(entities)})
 }
 
+// logAudit records one audit entry for a completed anonymize/analyze
+// call. Failures are logged but never fail the request — the audit log
+// is best-effort and must not become an availability dependency.
+func (h *ProxyHandler) logAudit(endpoint, text string, entities []Entity, r *http.Request) {
+	if h.audit == nil {
+		return
+	}
+
+	seen := make(map[string]bool, len(entities))
+	var types []string
+	for _, e := range entities {
+		if !seen[e.EntityType] {
+			seen[e.EntityType] = true
+			types = append(types, e.EntityType)
+		}
+	}
+
+	entry := audit.Entry{
+		Timestamp:     time.Now().UTC(),
+		Endpoint:      endpoint,
+		TextHash:      h.audit.HashText(text),
+		EntityTypes:   types,
+		EntityCount:   len(entities),
+		CallerIP:      callerIP(r),
+		ConfigVersion: h.config.Version(),
+	}
+	if _, err := h.audit.Append(entry); err != nil {
+		log.Printf("audit log append failed: %v", err)
+	}
+}
+
+// callerIP extracts the caller's address, preferring X-Forwarded-For
+// since the proxy is commonly deployed behind another reverse proxy.
+func callerIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	return r.RemoteAddr
+}
+
 // HandleHealth returns proxy and Presidio health status.
 func (h *ProxyHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	presidioStatus := "ok"
-	if err := h.presidio.Health(); err != nil {
+	if h.sidecar != nil {
+		presidioStatus = h.sidecar.Status()
+	} else if err := h.presidio.Health(); err != nil {
 		presidioStatus = "down"
 	}
 
@@ -86,11 +194,68 @@ func (h *ProxyHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]string{"status": status, "presidio": presidioStatus})
 }
 
+// HandleSidecarLogs returns the last ~64KB of captured Presidio stderr,
+// useful for diagnosing a restart or crash loop without shell access to
+// the host.
+func (h *ProxyHandler) HandleSidecarLogs(w http.ResponseWriter, r *http.Request) {
+	if h.sidecar == nil {
+		http.Error(w, `{"error":"sidecar supervisor not configured"}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(h.sidecar.Logs()))
+}
+
+// waitForSidecar blocks briefly for the sidecar to recover from a
+// restart. If it doesn't come back within restartWaitTimeout, it writes
+// a 503 with Retry-After and returns false so the caller can bail out.
+func (h *ProxyHandler) waitForSidecar(w http.ResponseWriter) bool {
+	if h.sidecar == nil {
+		return true
+	}
+	if err := h.sidecar.WaitReady(restartWaitTimeout); err != nil {
+		w.Header().Set("Retry-After", "3")
+		http.Error(w, `{"error":"presidio sidecar is restarting, try again shortly"}`, http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
 // HandleGetConfig returns current entity type configuration.
 func (h *ProxyHandler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, h.config.GetEntities())
 }
 
+// HandleGetOperators returns the operator configured for each entity
+// type that has a non-default one set.
+func (h *ProxyHandler) HandleGetOperators(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.config.GetOperators())
+}
+
+// HandleSetOperators updates the operator used for one or more entity
+// types, e.g. {"CREDIT_CARD": {"type": "mask", "mask_keep": 4}}.
+func (h *ProxyHandler) HandleSetOperators(w http.ResponseWriter, r *http.Request) {
+	var updates map[string]EntityOperator
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	for entity, op := range updates {
+		h.config.SetOperator(entity, op)
+	}
+	h.recordConfigMutation()
+
+	writeJSON(w, h.config.GetOperators())
+}
+
+// recordConfigMutation notes a config change in metrics, if configured.
+func (h *ProxyHandler) recordConfigMutation() {
+	if h.metrics != nil {
+		h.metrics.IncConfigMutations()
+	}
+}
+
 // HandleSetConfig updates entity type configuration.
 func (h *ProxyHandler) HandleSetConfig(w http.ResponseWriter, r *http.Request) {
 	var updates map[string]bool
@@ -102,6 +267,7 @@ func (h *ProxyHandler) HandleSetConfig(w http.ResponseWriter, r *http.Request) {
 	for entity, enabled := range updates {
 		h.config.SetEntity(entity, enabled)
 	}
+	h.recordConfigMutation()
 
 	writeJSON(w, h.config.GetEntities())
 }
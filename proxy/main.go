@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rand"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+
+	"github.com/Mr-Neutr0n/pii-guard/audit"
 )
 
 func main() {
@@ -23,27 +26,59 @@ func main() {
 		projectDir = envDir
 	}
 
+	metrics := NewMetrics()
+
 	// Start Presidio sidecar
-	sidecar := NewSidecarManager(projectDir)
+	sidecar := NewSidecarManager(projectDir, metrics)
 	if err := sidecar.Start(); err != nil {
 		log.Fatalf("Failed to start Presidio: %v", err)
 	}
 
 	// Initialize components
 	config := NewConfig()
-	presidio := NewPresidioClient()
-	handler := &ProxyHandler{presidio: presidio, config: config}
+	presidio := NewPresidioClient(metrics)
+
+	auditStore, err := audit.NewStore(filepath.Join(projectDir, "audit.jsonl"), auditSalt())
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+
+	operators, err := NewOperatorEngine(operatorHMACKey(), operatorAESKey())
+	if err != nil {
+		log.Fatalf("Failed to initialize operator engine: %v", err)
+	}
+
+	handler := &ProxyHandler{presidio: presidio, config: config, audit: auditStore, operators: operators, metrics: metrics, sidecar: sidecar}
+	openaiProxy := NewOpenAIProxy(presidio, config, os.Getenv("UPSTREAM_LLM_URL"), os.Getenv("UPSTREAM_LLM_API_KEY"))
 
 	// Set up routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /anonymize", handler.HandleAnonymize)
 	mux.HandleFunc("POST /analyze", handler.HandleAnalyze)
+	mux.HandleFunc("POST /deanonymize", handler.HandleDeanonymize)
+	mux.HandleFunc("POST /v1/chat/completions", openaiProxy.HandleChatCompletions)
+	mux.HandleFunc("POST /v1/completions", openaiProxy.HandleCompletions)
 	mux.HandleFunc("GET /health", handler.HandleHealth)
 	mux.HandleFunc("GET /config", handler.HandleGetConfig)
 	mux.HandleFunc("PUT /config", handler.HandleSetConfig)
+	mux.HandleFunc("GET /config/operators", handler.HandleGetOperators)
+	mux.HandleFunc("PUT /config/operators", handler.HandleSetOperators)
+	mux.HandleFunc("GET /ws/anonymize", handler.HandleWebSocketAnonymize)
+
+	// CORS + request-count instrumentation
+	corsHandler := corsMiddleware(metricsMiddleware(metrics, mux))
 
-	// CORS middleware
-	corsHandler := corsMiddleware(mux)
+	// Admin listener: Prometheus metrics, pprof, the audit log and sidecar
+	// logs. Bearer-token gated and bound to loopback only, since all of
+	// these can leak sensitive information.
+	adminToken := adminToken()
+	go func() {
+		adminAddr := "127.0.0.1:9402"
+		log.Printf("Admin listener (metrics + pprof + audit + sidecar logs) on %s", adminAddr)
+		if err := http.ListenAndServe(adminAddr, newAdminMux(metrics, handler, adminToken)); err != nil {
+			log.Printf("Admin listener stopped: %v", err)
+		}
+	}()
 
 	// Graceful shutdown
 	sigCh := make(chan os.Signal, 1)
@@ -60,6 +95,70 @@ func main() {
 	log.Fatal(http.ListenAndServe(addr, corsHandler))
 }
 
+// auditSalt returns the HMAC key used to hash request text before it's
+// written to the audit log. Set AUDIT_HASH_SALT in production so the
+// salt survives restarts; otherwise a random salt is generated per
+// process, which is fine for local development but makes TextHash values
+// incomparable across restarts.
+func auditSalt() []byte {
+	if v := os.Getenv("AUDIT_HASH_SALT"); v != "" {
+		return []byte(v)
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		log.Fatalf("Failed to generate audit salt: %v", err)
+	}
+	log.Println("AUDIT_HASH_SALT not set — using a random per-process salt")
+	return salt
+}
+
+// operatorHMACKey loads the key used by the "hash" operator from
+// OPERATOR_HMAC_KEY. Returns nil if unset, in which case hash-type
+// operators will fail at request time rather than at startup, since a
+// deployment may simply never use them.
+func operatorHMACKey() []byte {
+	if v := os.Getenv("OPERATOR_HMAC_KEY"); v != "" {
+		return []byte(v)
+	}
+	return nil
+}
+
+// operatorAESKey loads the key used by the "encrypt" operator from
+// OPERATOR_AES_KEY (16, 24 or 32 bytes, e.g. pulled from a KMS-backed
+// secret at deploy time). Returns nil if unset.
+func operatorAESKey() []byte {
+	if v := os.Getenv("OPERATOR_AES_KEY"); v != "" {
+		return []byte(v)
+	}
+	return nil
+}
+
+// metricsMiddleware records one request-count observation per call,
+// keyed by "METHOD /path".
+func metricsMiddleware(metrics *Metrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.IncRequests(r.Method + " " + r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminToken loads the bearer token required by the admin listener from
+// ADMIN_TOKEN. If unset, a random token is generated and logged once —
+// fine for local development, but production deployments should set
+// ADMIN_TOKEN explicitly so it survives restarts.
+func adminToken() string {
+	if v := os.Getenv("ADMIN_TOKEN"); v != "" {
+		return v
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		log.Fatalf("Failed to generate admin token: %v", err)
+	}
+	tokenHex := fmt.Sprintf("%x", token)
+	log.Printf("ADMIN_TOKEN not set — generated one-time admin token: %s", tokenHex)
+	return tokenHex
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// latencyBuckets are the upper bounds (seconds) for the Presidio latency
+// histogram, roughly log-spaced from 5ms to 5s.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Metrics is a small hand-rolled Prometheus exporter — the proxy has no
+// other third-party metrics dependency, so counters/gauges/histograms are
+// tracked directly with atomics and rendered in the text exposition
+// format on demand.
+type Metrics struct {
+	mu sync.Mutex
+
+	requestsTotal   map[string]*uint64 // keyed by HTTP endpoint
+	entityCounts    map[string]*uint64 // keyed by entity type
+	presidioLatency bucketedHistogram
+	sidecarRestarts uint64
+	sidecarHealthy  int32 // 0 or 1, via atomic
+	configMutations uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requestsTotal:   make(map[string]*uint64),
+		entityCounts:    make(map[string]*uint64),
+		presidioLatency: newBucketedHistogram(latencyBuckets),
+		sidecarHealthy:  1,
+	}
+}
+
+// IncRequests increments the request counter for endpoint.
+func (m *Metrics) IncRequests(endpoint string) {
+	atomic.AddUint64(m.counter(&m.requestsTotal, endpoint), 1)
+}
+
+// IncEntityDetections adds n detections of entityType to the running count.
+func (m *Metrics) IncEntityDetections(entityType string, n int) {
+	if n <= 0 {
+		return
+	}
+	atomic.AddUint64(m.counter(&m.entityCounts, entityType), uint64(n))
+}
+
+// ObservePresidioLatency records one round-trip duration, in seconds.
+func (m *Metrics) ObservePresidioLatency(seconds float64) {
+	m.presidioLatency.observe(seconds)
+}
+
+// IncSidecarRestarts increments the sidecar restart counter.
+func (m *Metrics) IncSidecarRestarts() {
+	atomic.AddUint64(&m.sidecarRestarts, 1)
+}
+
+// SetSidecarHealthy sets the sidecar health gauge.
+func (m *Metrics) SetSidecarHealthy(healthy bool) {
+	v := int32(0)
+	if healthy {
+		v = 1
+	}
+	atomic.StoreInt32(&m.sidecarHealthy, v)
+}
+
+// IncConfigMutations increments the config-mutation counter.
+func (m *Metrics) IncConfigMutations() {
+	atomic.AddUint64(&m.configMutations, 1)
+}
+
+// counter returns the *uint64 for key in a lazily-populated map,
+// creating it under m.mu if it doesn't exist yet.
+func (m *Metrics) counter(target *map[string]*uint64, key string) *uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := (*target)[key]; ok {
+		return c
+	}
+	c := new(uint64)
+	(*target)[key] = c
+	return c
+}
+
+// WriteText renders all metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteText(w io.Writer) {
+	fmt.Fprintln(w, "# HELP piiguard_requests_total Requests handled, by endpoint.")
+	fmt.Fprintln(w, "# TYPE piiguard_requests_total counter")
+	m.writeLabeledCounters(w, "piiguard_requests_total", "endpoint", m.requestsTotal)
+
+	fmt.Fprintln(w, "# HELP piiguard_entities_detected_total PII entities detected, by type.")
+	fmt.Fprintln(w, "# TYPE piiguard_entities_detected_total counter")
+	m.writeLabeledCounters(w, "piiguard_entities_detected_total", "entity_type", m.entityCounts)
+
+	fmt.Fprintln(w, "# HELP piiguard_presidio_latency_seconds Presidio round-trip latency.")
+	fmt.Fprintln(w, "# TYPE piiguard_presidio_latency_seconds histogram")
+	m.presidioLatency.writeText(w, "piiguard_presidio_latency_seconds")
+
+	fmt.Fprintln(w, "# HELP piiguard_sidecar_restarts_total Presidio sidecar restarts.")
+	fmt.Fprintln(w, "# TYPE piiguard_sidecar_restarts_total counter")
+	fmt.Fprintf(w, "piiguard_sidecar_restarts_total %d\n", atomic.LoadUint64(&m.sidecarRestarts))
+
+	fmt.Fprintln(w, "# HELP piiguard_sidecar_healthy Whether the Presidio sidecar is currently healthy.")
+	fmt.Fprintln(w, "# TYPE piiguard_sidecar_healthy gauge")
+	fmt.Fprintf(w, "piiguard_sidecar_healthy %d\n", atomic.LoadInt32(&m.sidecarHealthy))
+
+	fmt.Fprintln(w, "# HELP piiguard_config_mutations_total Config (entity/operator) mutations applied.")
+	fmt.Fprintln(w, "# TYPE piiguard_config_mutations_total counter")
+	fmt.Fprintf(w, "piiguard_config_mutations_total %d\n", atomic.LoadUint64(&m.configMutations))
+}
+
+func (m *Metrics) writeLabeledCounters(w io.Writer, name, label string, counters map[string]*uint64) {
+	m.mu.Lock()
+	keys := make([]string, 0, len(counters))
+	for k := range counters {
+		keys = append(keys, k)
+	}
+	m.mu.Unlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, k, atomic.LoadUint64(counters[k]))
+	}
+}
+
+// bucketedHistogram is a fixed-bucket cumulative histogram, the same
+// shape Prometheus client libraries produce.
+type bucketedHistogram struct {
+	bounds []float64
+	counts []uint64 // counts[i] = observations <= bounds[i]
+	sum    uint64   // bits of a float64, via atomic
+	total  uint64
+}
+
+func newBucketedHistogram(bounds []float64) bucketedHistogram {
+	return bucketedHistogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *bucketedHistogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			atomic.AddUint64(&h.counts[i], 1)
+		}
+	}
+	atomic.AddUint64(&h.total, 1)
+	addFloatBits(&h.sum, v)
+}
+
+func (h *bucketedHistogram) writeText(w io.Writer, name string) {
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, trimFloat(bound), atomic.LoadUint64(&h.counts[i]))
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, atomic.LoadUint64(&h.total))
+	fmt.Fprintf(w, "%s_sum %v\n", name, loadFloatBits(&h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, atomic.LoadUint64(&h.total))
+}
+
+func trimFloat(f float64) string {
+	return fmt.Sprintf("%g", f)
+}
+
+// addFloatBits atomically adds delta to the float64 stored in bits.
+func addFloatBits(bits *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		newVal := math.Float64frombits(old) + delta
+		if atomic.CompareAndSwapUint64(bits, old, math.Float64bits(newVal)) {
+			return
+		}
+	}
+}
+
+func loadFloatBits(bits *uint64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(bits))
+}
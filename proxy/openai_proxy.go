@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenAIProxy forwards OpenAI-compatible chat/completions requests to an
+// upstream LLM, scrubbing PII out of the outbound request and rehydrating
+// the original text back into the (possibly streamed) response, so
+// callers can point any OpenAI SDK at PII Guard without changing code.
+type OpenAIProxy struct {
+	presidio    *PresidioClient
+	config      *Config
+	client      *http.Client
+	upstreamURL string
+	apiKey      string
+}
+
+// NewOpenAIProxy builds a proxy that forwards to upstreamURL (e.g.
+// https://api.openai.com/v1/chat/completions), attaching apiKey as a
+// bearer token if set.
+func NewOpenAIProxy(presidio *PresidioClient, config *Config, upstreamURL, apiKey string) *OpenAIProxy {
+	return &OpenAIProxy{
+		presidio:    presidio,
+		config:      config,
+		client:      &http.Client{Timeout: 120 * time.Second},
+		upstreamURL: upstreamURL,
+		apiKey:      apiKey,
+	}
+}
+
+// placeholderManifest maps the tokens substituted into a request back to
+// the original text, so the response can be rehydrated before it reaches
+// the caller. It is scoped to a single request.
+type placeholderManifest map[string]string
+
+// scrub replaces every detected PII span in text with a unique
+// placeholder token and records the mapping in m. Entity.Start/End are
+// Presidio's offsets, which count Unicode codepoints, not bytes, so
+// spans are resolved against []rune(text) the same way
+// OperatorEngine.Apply does — a multi-byte rune anywhere before an
+// entity would otherwise shift every later entity's offset and corrupt
+// both the scrubbed text and the manifest's "original" value.
+func (p *OpenAIProxy) scrub(text string, m placeholderManifest) (string, error) {
+	if text == "" {
+		return text, nil
+	}
+	entities, err := p.presidio.Analyze(text)
+	if err != nil {
+		return "", fmt.Errorf("analyze: %w", err)
+	}
+	if len(entities) == 0 {
+		return text, nil
+	}
+
+	// Replace back-to-front so earlier offsets stay valid.
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Start < entities[j].Start })
+	out := []rune(text)
+	for i := len(entities) - 1; i >= 0; i-- {
+		e := entities[i]
+		if e.Start < 0 || e.End > len(out) || e.Start > e.End {
+			continue
+		}
+		token := fmt.Sprintf("⟦PII_%d_%d⟧", len(m), e.Start)
+		m[token] = string(out[e.Start:e.End])
+
+		merged := make([]rune, 0, len(out)-(e.End-e.Start)+len([]rune(token)))
+		merged = append(merged, out[:e.Start]...)
+		merged = append(merged, []rune(token)...)
+		merged = append(merged, out[e.End:]...)
+		out = merged
+	}
+	return string(out), nil
+}
+
+// rehydrate replaces every placeholder token in text with its original
+// value from m.
+func rehydrate(text string, m placeholderManifest) string {
+	for token, original := range m {
+		text = strings.ReplaceAll(text, token, original)
+	}
+	return text
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// HandleChatCompletions proxies POST /v1/chat/completions.
+func (p *OpenAIProxy) HandleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	p.proxyCompletion(w, r, "messages", func(body map[string]any, manifest placeholderManifest) error {
+		raw, ok := body["messages"]
+		if !ok {
+			return nil
+		}
+		items, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("messages must be an array")
+		}
+		for _, item := range items {
+			msg, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			content, ok := msg["content"].(string)
+			if !ok {
+				continue
+			}
+			scrubbed, err := p.scrub(content, manifest)
+			if err != nil {
+				return err
+			}
+			msg["content"] = scrubbed
+		}
+		return nil
+	})
+}
+
+// HandleCompletions proxies POST /v1/completions (the legacy `prompt`
+// field rather than `messages`).
+func (p *OpenAIProxy) HandleCompletions(w http.ResponseWriter, r *http.Request) {
+	p.proxyCompletion(w, r, "prompt", func(body map[string]any, manifest placeholderManifest) error {
+		prompt, ok := body["prompt"].(string)
+		if !ok {
+			return nil
+		}
+		scrubbed, err := p.scrub(prompt, manifest)
+		if err != nil {
+			return err
+		}
+		body["prompt"] = scrubbed
+		return nil
+	})
+}
+
+// proxyCompletion decodes the request body, applies scrub to mutate it in
+// place, forwards it upstream, and streams back the (rehydrated)
+// response. field is only used in error messages.
+func (p *OpenAIProxy) proxyCompletion(w http.ResponseWriter, r *http.Request, field string, scrubBody func(map[string]any, placeholderManifest) error) {
+	if p.upstreamURL == "" {
+		http.Error(w, `{"error":"UPSTREAM_LLM_URL is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	manifest := placeholderManifest{}
+	if err := scrubBody(body, manifest); err != nil {
+		log.Printf("openai proxy: failed to scrub %s: %v", field, err)
+		http.Error(w, `{"error":"failed to scrub request"}`, http.StatusBadGateway)
+		return
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, `{"error":"failed to encode request"}`, http.StatusInternalServerError)
+		return
+	}
+
+	upstreamReq, err := http.NewRequest(http.MethodPost, p.upstreamURL, bytes.NewReader(payload))
+	if err != nil {
+		http.Error(w, `{"error":"failed to build upstream request"}`, http.StatusInternalServerError)
+		return
+	}
+	upstreamReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		upstreamReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(upstreamReq)
+	if err != nil {
+		log.Printf("openai proxy: upstream request failed: %v", err)
+		http.Error(w, `{"error":"upstream request failed"}`, http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		streamSSE(w, resp.Body, manifest)
+		return
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, `{"error":"failed to read upstream response"}`, http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.StatusCode)
+	w.Write([]byte(rehydrate(string(data), manifest)))
+}
+
+// streamSSE passes through an upstream `text/event-stream` response
+// event by event, rehydrating placeholders in each choice's streamed
+// content. A placeholder token can land split across two events — real
+// token streaming routinely breaks a model's echoed input mid-token — so
+// each choice (keyed by its index, since n>1 streams interleave) carries
+// forward any suffix that might be the start of an unterminated
+// "⟦PII_n_n⟧" token until a later chunk completes or disproves it.
+func streamSSE(w http.ResponseWriter, upstream io.Reader, manifest placeholderManifest) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	carry := map[int]string{}
+	scanner := bufio.NewScanner(upstream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok || payload == "[DONE]" {
+			fmt.Fprintln(w, line)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			continue
+		}
+
+		var event map[string]any
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			fmt.Fprintln(w, line) // not an event we understand, pass through verbatim
+			if flusher != nil {
+				flusher.Flush()
+			}
+			continue
+		}
+
+		rehydrateSSEEvent(event, carry, manifest)
+		writeSSEEvent(w, event, line)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for idx, pending := range carry {
+		if pending == "" {
+			continue
+		}
+		// The stream ended with an unterminated token and no further
+		// chunk to complete it — flush it rehydrated rather than
+		// silently dropping it.
+		log.Printf("openai proxy: stream ended mid-placeholder for choice %d, flushing as-is", idx)
+		fmt.Fprintln(w, "data: "+rehydrate(pending, manifest))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// rehydrateSSEEvent rewrites each choice's streamed content in place: it
+// prepends whatever was held back from the previous chunk for that
+// choice, rehydrates and emits everything up to the last safe boundary,
+// and leaves any unterminated placeholder suffix in carry for next time.
+func rehydrateSSEEvent(event map[string]any, carry map[int]string, manifest placeholderManifest) {
+	choices, ok := event["choices"].([]any)
+	if !ok {
+		return
+	}
+
+	for _, c := range choices {
+		choice, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		idx := choiceIndex(choice)
+
+		if delta, ok := choice["delta"].(map[string]any); ok {
+			if content, ok := delta["content"].(string); ok {
+				var safe string
+				safe, carry[idx] = splitSSEContent(carry[idx] + content)
+				delta["content"] = rehydrate(safe, manifest)
+			}
+		}
+		if text, ok := choice["text"].(string); ok {
+			var safe string
+			safe, carry[idx] = splitSSEContent(carry[idx] + text)
+			choice["text"] = rehydrate(safe, manifest)
+		}
+	}
+}
+
+// choiceIndex reads a streamed choice's "index" field, defaulting to 0
+// for the common single-choice case.
+func choiceIndex(choice map[string]any) int {
+	if idx, ok := choice["index"].(float64); ok {
+		return int(idx)
+	}
+	return 0
+}
+
+// splitSSEContent returns the prefix of text that's safe to rehydrate
+// and emit now, plus whatever tail must be held back because it could be
+// the start of a placeholder token still arriving in a later chunk.
+// Placeholder tokens are delimited by ⟦ ⟧, so the only ambiguous case is
+// a trailing ⟦ with no matching ⟧ yet.
+func splitSSEContent(text string) (safe, carry string) {
+	if idx := strings.LastIndex(text, "⟦"); idx != -1 && !strings.Contains(text[idx:], "⟧") {
+		return text[:idx], text[idx:]
+	}
+	return text, ""
+}
+
+// writeSSEEvent marshals event back to an SSE data line without HTML
+// escaping (so rehydrated text survives intact), falling back to the
+// original line verbatim if marshaling somehow fails.
+func writeSSEEvent(w http.ResponseWriter, event map[string]any, original string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(event); err != nil {
+		fmt.Fprintln(w, original)
+		return
+	}
+	fmt.Fprint(w, "data: "+buf.String())
+}
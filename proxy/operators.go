@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// OperatorType selects how a detected entity's text is transformed in
+// the anonymized output.
+type OperatorType string
+
+const (
+	OperatorReplace OperatorType = "replace" // <ENTITY_TYPE>, the historical default
+	OperatorMask    OperatorType = "mask"    // keep the last MaskKeep chars, mask the rest
+	OperatorHash    OperatorType = "hash"    // deterministic HMAC-SHA256 token
+	OperatorRedact  OperatorType = "redact"  // drop the span entirely
+	OperatorEncrypt OperatorType = "encrypt" // reversible AES-GCM token, see /deanonymize
+)
+
+// EntityOperator configures how one entity type is anonymized.
+type EntityOperator struct {
+	Type     OperatorType `json:"type"`
+	MaskKeep int          `json:"mask_keep,omitempty"` // chars kept for OperatorMask, default 4
+}
+
+// OperatorEngine applies EntityOperators to detected entities, producing
+// anonymized text plus a manifest of encrypted tokens that can later be
+// reversed via Decrypt.
+type OperatorEngine struct {
+	hmacKey []byte
+	gcm     cipher.AEAD
+}
+
+// NewOperatorEngine builds an engine from a server-side HMAC key (for
+// OperatorHash) and a 16/24/32-byte AES key (for OperatorEncrypt). Either
+// key may be nil if that operator type won't be used.
+func NewOperatorEngine(hmacKey, aesKey []byte) (*OperatorEngine, error) {
+	e := &OperatorEngine{hmacKey: hmacKey}
+	if len(aesKey) > 0 {
+		block, err := aes.NewCipher(aesKey)
+		if err != nil {
+			return nil, fmt.Errorf("build AES cipher: %w", err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("build AES-GCM: %w", err)
+		}
+		e.gcm = gcm
+	}
+	return e, nil
+}
+
+// Apply rewrites text by replacing each entity span with the output of
+// its configured operator. Entity.Start/End are Presidio's offsets,
+// which count Unicode codepoints, not bytes — so spans are resolved
+// against []rune(text), not byte slices, or a multi-byte rune anywhere
+// before an entity would shift every later entity's offset and redact
+// the wrong bytes. Entities are processed in descending start order so
+// earlier offsets stay valid. Returns the anonymized text and a
+// manifest of placeholder -> encrypted payload for any OperatorEncrypt
+// tokens produced.
+func (e *OperatorEngine) Apply(text string, entities []Entity, ops func(entityType string) EntityOperator) (string, map[string]string, error) {
+	ordered := make([]Entity, len(entities))
+	copy(ordered, entities)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Start > ordered[j].Start })
+
+	manifest := make(map[string]string)
+	out := []rune(text)
+	for _, ent := range ordered {
+		if ent.Start < 0 || ent.End > len(out) || ent.Start > ent.End {
+			continue
+		}
+		original := string(out[ent.Start:ent.End])
+		op := ops(ent.EntityType)
+
+		replacement, err := e.render(ent.EntityType, original, op, manifest)
+		if err != nil {
+			return "", nil, err
+		}
+
+		merged := make([]rune, 0, len(out)-(ent.End-ent.Start)+len([]rune(replacement)))
+		merged = append(merged, out[:ent.Start]...)
+		merged = append(merged, []rune(replacement)...)
+		merged = append(merged, out[ent.End:]...)
+		out = merged
+	}
+	return string(out), manifest, nil
+}
+
+func (e *OperatorEngine) render(entityType, original string, op EntityOperator, manifest map[string]string) (string, error) {
+	switch op.Type {
+	case OperatorMask:
+		keep := op.MaskKeep
+		if keep <= 0 {
+			keep = 4
+		}
+		return maskKeepSuffix(original, keep), nil
+
+	case OperatorHash:
+		if len(e.hmacKey) == 0 {
+			return "", fmt.Errorf("hash operator requested for %s but no HMAC key is configured", entityType)
+		}
+		mac := hmac.New(sha256.New, e.hmacKey)
+		mac.Write([]byte(original))
+		return fmt.Sprintf("<%s_%s>", entityType, hex.EncodeToString(mac.Sum(nil))[:12]), nil
+
+	case OperatorRedact:
+		return "", nil
+
+	case OperatorEncrypt:
+		token, payload, err := e.encrypt(original)
+		if err != nil {
+			return "", err
+		}
+		manifest[token] = payload
+		return token, nil
+
+	case OperatorReplace, "":
+		return "<" + entityType + ">", nil
+
+	default:
+		return "", fmt.Errorf("unknown operator %q for %s", op.Type, entityType)
+	}
+}
+
+// maskKeepSuffix masks all but the last keep characters of s, e.g.
+// maskKeepSuffix("4111111111111234", 4) == "************1234".
+func maskKeepSuffix(s string, keep int) string {
+	runes := []rune(s)
+	if keep >= len(runes) {
+		return s
+	}
+	masked := strings.Repeat("*", len(runes)-keep)
+	return masked + string(runes[len(runes)-keep:])
+}
+
+// encrypt produces a unique placeholder token and the base64(nonce ||
+// ciphertext) payload that Decrypt can later reverse.
+func (e *OperatorEngine) encrypt(plaintext string) (token, payload string, err error) {
+	if e.gcm == nil {
+		return "", "", fmt.Errorf("encrypt operator requested but no AES key is configured")
+	}
+	nonce := make([]byte, e.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := e.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	payload = base64.StdEncoding.EncodeToString(sealed)
+	token = "<ENC_" + payload[:12] + ">"
+	return token, payload, nil
+}
+
+// Decrypt reverses an OperatorEncrypt payload produced by encrypt.
+func (e *OperatorEngine) Decrypt(payload string) (string, error) {
+	if e.gcm == nil {
+		return "", fmt.Errorf("no AES key is configured")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("decode payload: %w", err)
+	}
+	nonceSize := e.gcm.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("payload too short")
+	}
+	nonce, ciphertext := sealed[:nonceSize], sealed[nonceSize:]
+	plaintext, err := e.gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
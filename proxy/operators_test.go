@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+// TestApplyUsesRuneOffsets guards against the regression where entity
+// spans were resolved against byte offsets: a multi-byte rune before an
+// entity shifts every later byte offset, corrupting the replacement.
+func TestApplyUsesRuneOffsets(t *testing.T) {
+	engine, err := NewOperatorEngine(nil, nil)
+	if err != nil {
+		t.Fatalf("NewOperatorEngine: %v", err)
+	}
+
+	text := "José's card is 4111111111111234 ok"
+	runes := []rune(text)
+	start, end := -1, -1
+	for i, r := range runes {
+		if r == '4' {
+			start = i
+			break
+		}
+	}
+	for i := start; i < len(runes); i++ {
+		if runes[i] == ' ' {
+			end = i
+			break
+		}
+	}
+	if start == -1 || end == -1 {
+		t.Fatal("test setup: couldn't locate the card number span")
+	}
+
+	entities := []Entity{{EntityType: "CREDIT_CARD", Start: start, End: end}}
+	out, _, err := engine.Apply(text, entities, func(string) EntityOperator {
+		return EntityOperator{Type: OperatorReplace}
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	want := "José's card is <CREDIT_CARD> ok"
+	if out != want {
+		t.Fatalf("Apply(%q) = %q, want %q", text, out, want)
+	}
+}
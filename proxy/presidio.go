@@ -13,7 +13,8 @@ const presidioBaseURL = "http://127.0.0.1:9401"
 
 // PresidioClient communicates with the Presidio engine over HTTP.
 type PresidioClient struct {
-	client *http.Client
+	client  *http.Client
+	metrics *Metrics
 }
 
 type PresidioAnalyzeRequest struct {
@@ -37,13 +38,15 @@ type PresidioAnonymizeResponse struct {
 	Count    int      `json:"count"`
 }
 
-func NewPresidioClient() *PresidioClient {
+func NewPresidioClient(metrics *Metrics) *PresidioClient {
 	return &PresidioClient{
-		client: &http.Client{Timeout: 10 * time.Second},
+		client:  &http.Client{Timeout: 10 * time.Second},
+		metrics: metrics,
 	}
 }
 
 func (p *PresidioClient) Anonymize(text string) (*PresidioAnonymizeResponse, error) {
+	start := time.Now()
 	reqBody := PresidioAnalyzeRequest{
 		Text:           text,
 		Language:       "en",
@@ -69,10 +72,12 @@ func (p *PresidioClient) Anonymize(text string) (*PresidioAnonymizeResponse, err
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
+	p.recordMetrics(start, result.Entities)
 	return &result, nil
 }
 
 func (p *PresidioClient) Analyze(text string) ([]Entity, error) {
+	start := time.Now()
 	reqBody := PresidioAnalyzeRequest{
 		Text:           text,
 		Language:       "en",
@@ -100,9 +105,26 @@ func (p *PresidioClient) Analyze(text string) ([]Entity, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
+	p.recordMetrics(start, result.Entities)
 	return result.Entities, nil
 }
 
+// recordMetrics logs round-trip latency and per-entity-type detection
+// counts for one Presidio call. No-op if metrics weren't configured.
+func (p *PresidioClient) recordMetrics(start time.Time, entities []Entity) {
+	if p.metrics == nil {
+		return
+	}
+	p.metrics.ObservePresidioLatency(time.Since(start).Seconds())
+	counts := make(map[string]int, len(entities))
+	for _, e := range entities {
+		counts[e.EntityType]++
+	}
+	for entityType, n := range counts {
+		p.metrics.IncEntityDetections(entityType, n)
+	}
+}
+
 func (p *PresidioClient) Health() error {
 	resp, err := p.client.Get(presidioBaseURL + "/health")
 	if err != nil {
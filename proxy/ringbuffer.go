@@ -0,0 +1,57 @@
+package main
+
+import "sync"
+
+// ringBuffer is a fixed-size byte buffer that keeps only the most
+// recently written data, used to capture a bounded tail of sidecar
+// stderr across restarts without growing unbounded.
+type ringBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size), size: size}
+}
+
+// Write implements io.Writer. It never returns an error; if p is larger
+// than the buffer, only its tail is kept.
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(p) >= r.size {
+		copy(r.buf, p[len(p)-r.size:])
+		r.pos = 0
+		r.full = true
+		return len(p), nil
+	}
+
+	n := copy(r.buf[r.pos:], p)
+	if n < len(p) {
+		r.full = true
+		copy(r.buf, p[n:])
+		r.pos = len(p) - n
+	} else {
+		r.pos += n
+		if r.pos == r.size {
+			r.pos = 0
+			r.full = true
+		}
+	}
+	return len(p), nil
+}
+
+// String returns the buffered bytes in write order.
+func (r *ringBuffer) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return string(r.buf[:r.pos])
+	}
+	return string(r.buf[r.pos:]) + string(r.buf[:r.pos])
+}
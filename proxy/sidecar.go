@@ -2,69 +2,280 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
-// SidecarManager manages the Presidio Python process lifecycle.
+const (
+	healthCheckInterval = 5 * time.Second
+	unhealthyThreshold  = 3 // consecutive failed checks before restarting
+	maxBackoff          = 60 * time.Second
+	crashLoopWindow     = 5 * time.Minute
+	crashLoopThreshold  = 5 // restarts within crashLoopWindow before declaring a crash loop
+	restartWaitTimeout  = 3 * time.Second
+	stderrRingSize      = 64 * 1024
+)
+
+// SidecarManager supervises the Presidio Python process: it restarts the
+// process with exponential backoff when health checks fail, declares a
+// crash loop if restarts happen too often, and gives callers a way to
+// wait briefly for the sidecar to come back instead of failing instantly.
 type SidecarManager struct {
-	cmd        *exec.Cmd
-	running    bool
 	projectDir string
+	metrics    *Metrics
+	stderr     *ringBuffer
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	running   bool
+	healthy   bool
+	readyCh   chan struct{} // closed while healthy; swapped for a fresh one on failure
+	restarts  []time.Time   // restart timestamps within crashLoopWindow, for crash-loop detection
+	crashLoop bool
+	stopCh    chan struct{}
 }
 
-func NewSidecarManager(projectDir string) *SidecarManager {
-	return &SidecarManager{projectDir: projectDir}
+func NewSidecarManager(projectDir string, metrics *Metrics) *SidecarManager {
+	return &SidecarManager{
+		projectDir: projectDir,
+		metrics:    metrics,
+		stderr:     newRingBuffer(stderrRingSize),
+		readyCh:    make(chan struct{}),
+		stopCh:     make(chan struct{}),
+	}
 }
 
+// Start launches the Presidio process (or adopts one already running
+// externally), waits for it to become healthy, then starts the
+// background supervisor loop that keeps it that way.
 func (s *SidecarManager) Start() error {
-	// Check if Presidio is already running externally
 	if s.isHealthy() {
 		log.Println("Presidio already running on :9401")
+		s.mu.Lock()
 		s.running = true
+		s.mu.Unlock()
+		s.setHealthy(true)
+		go s.supervise()
 		return nil
 	}
 
+	if err := s.spawn(); err != nil {
+		return err
+	}
+
+	if err := s.waitForHealthy(60 * time.Second); err != nil {
+		s.killProcess()
+		s.setHealthy(false)
+		return fmt.Errorf("Presidio failed to become healthy: %w", err)
+	}
+
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+	s.setHealthy(true)
+	log.Println("Presidio sidecar healthy on :9401")
+
+	go s.supervise()
+	return nil
+}
+
+// spawn starts the Presidio process, directing its stderr through the
+// ring buffer (as well as to this process's stderr) so the last ~64KB
+// survives a crash and is visible via GET /sidecar/logs.
+func (s *SidecarManager) spawn() error {
 	presidioDir := filepath.Join(s.projectDir, "presidio")
 	venvPython := filepath.Join(presidioDir, ".venv", "bin", "python")
 
-	// Check venv exists
 	if _, err := os.Stat(venvPython); os.IsNotExist(err) {
 		return fmt.Errorf("presidio venv not found at %s — run 'make setup' first", venvPython)
 	}
 
 	log.Println("Starting Presidio sidecar...")
-	s.cmd = exec.Command(venvPython, "app.py")
-	s.cmd.Dir = presidioDir
-	s.cmd.Stdout = os.Stdout
-	s.cmd.Stderr = os.Stderr
+	cmd := exec.Command(venvPython, "app.py")
+	cmd.Dir = presidioDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, s.stderr)
 
-	if err := s.cmd.Start(); err != nil {
+	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start Presidio: %w", err)
 	}
 
-	// Wait for health check (spaCy model load takes 5-10s)
-	if err := s.waitForHealthy(60 * time.Second); err != nil {
-		s.Stop()
-		return fmt.Errorf("Presidio failed to become healthy: %w", err)
+	s.mu.Lock()
+	s.cmd = cmd
+	s.mu.Unlock()
+	return nil
+}
+
+// supervise polls sidecar health on an interval and restarts it with
+// exponential backoff after unhealthyThreshold consecutive failures.
+func (s *SidecarManager) supervise() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	consecutiveFailures := 0
+	backoff := time.Second
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		if s.isHealthy() {
+			if consecutiveFailures > 0 {
+				log.Println("Presidio sidecar recovered")
+			}
+			consecutiveFailures = 0
+			backoff = time.Second
+			s.setHealthy(true)
+			s.refreshCrashLoop()
+			continue
+		}
+
+		consecutiveFailures++
+		if consecutiveFailures < unhealthyThreshold {
+			continue
+		}
+
+		s.setHealthy(false)
+		log.Printf("Presidio sidecar unhealthy (%d consecutive failures), restarting in %s", consecutiveFailures, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-s.stopCh:
+			return
+		}
+
+		s.killProcess()
+		s.recordRestart()
+		if err := s.spawn(); err != nil {
+			log.Printf("Presidio restart failed: %v", err)
+		} else if s.metrics != nil {
+			s.metrics.IncSidecarRestarts()
+		}
+
+		consecutiveFailures = 0
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
 	}
+}
 
-	s.running = true
-	log.Println("Presidio sidecar healthy on :9401")
-	return nil
+// recordRestart appends a restart timestamp and recomputes crashLoop.
+func (s *SidecarManager) recordRestart() {
+	now := time.Now()
+	s.mu.Lock()
+	s.restarts = append(s.trimRestarts(now), now)
+	s.crashLoop = len(s.restarts) >= crashLoopThreshold
+	s.mu.Unlock()
 }
 
-func (s *SidecarManager) Stop() {
-	if s.cmd != nil && s.cmd.Process != nil {
-		log.Println("Stopping Presidio sidecar...")
-		_ = s.cmd.Process.Kill()
-		_ = s.cmd.Wait()
-		s.running = false
+// refreshCrashLoop drops restart timestamps that have aged out of
+// crashLoopWindow and recomputes crashLoop accordingly. Without this,
+// crashLoop — once set by recordRestart — stayed true forever, since
+// nothing ever re-evaluated it after a later successful health check.
+func (s *SidecarManager) refreshCrashLoop() {
+	now := time.Now()
+	s.mu.Lock()
+	s.restarts = s.trimRestarts(now)
+	s.crashLoop = len(s.restarts) >= crashLoopThreshold
+	s.mu.Unlock()
+}
+
+// trimRestarts returns s.restarts with everything older than
+// crashLoopWindow (relative to now) dropped. Callers must hold s.mu.
+func (s *SidecarManager) trimRestarts(now time.Time) []time.Time {
+	cutoff := now.Add(-crashLoopWindow)
+	kept := s.restarts[:0]
+	for _, t := range s.restarts {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// Status reports the current supervisor state for GET /health.
+func (s *SidecarManager) Status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.crashLoop:
+		return "crash_loop"
+	case s.healthy:
+		return "ok"
+	default:
+		return "restarting"
+	}
+}
+
+// WaitReady blocks until the sidecar is healthy or timeout elapses,
+// letting callers queue briefly behind a restart instead of failing
+// immediately. Returns an error if the sidecar doesn't come back in time.
+func (s *SidecarManager) WaitReady(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.healthy {
+		s.mu.Unlock()
+		return nil
 	}
+	ch := s.readyCh
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("presidio sidecar unavailable")
+	}
+}
+
+// Logs returns the last ~64KB of captured sidecar stderr.
+func (s *SidecarManager) Logs() string {
+	return s.stderr.String()
+}
+
+func (s *SidecarManager) setHealthy(healthy bool) {
+	s.mu.Lock()
+	changed := s.healthy != healthy
+	s.healthy = healthy
+	if healthy && changed {
+		close(s.readyCh)
+	} else if !healthy && changed {
+		s.readyCh = make(chan struct{})
+	}
+	s.mu.Unlock()
+
+	if s.metrics != nil {
+		s.metrics.SetSidecarHealthy(healthy)
+	}
+}
+
+func (s *SidecarManager) killProcess() {
+	s.mu.Lock()
+	cmd := s.cmd
+	s.mu.Unlock()
+	if cmd != nil && cmd.Process != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}
+}
+
+// Stop halts the supervisor loop and kills the Presidio process.
+func (s *SidecarManager) Stop() {
+	close(s.stopCh)
+	log.Println("Stopping Presidio sidecar...")
+	s.killProcess()
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
 }
 
 func (s *SidecarManager) isHealthy() bool {
@@ -83,9 +294,11 @@ func (s *SidecarManager) waitForHealthy(timeout time.Duration) error {
 		if s.isHealthy() {
 			return nil
 		}
-		// Check if process died
-		if s.cmd.ProcessState != nil && s.cmd.ProcessState.Exited() {
-			return fmt.Errorf("presidio process exited with code %d", s.cmd.ProcessState.ExitCode())
+		s.mu.Lock()
+		cmd := s.cmd
+		s.mu.Unlock()
+		if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
+			return fmt.Errorf("presidio process exited with code %d", cmd.ProcessState.ExitCode())
 		}
 		time.Sleep(1 * time.Second)
 	}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// slidingWindow is how many trailing runes of text are held back from
+// each non-final flush instead of being committed to the client. It's
+// re-sent to Presidio along with the next chunk so a PII span split
+// across two chunks (e.g. a name broken before a middle initial, or an
+// email broken mid-token) still gets detected *before* any of it is
+// written to the socket — once a frame is sent there's no way to
+// retract it, so anything that might still be the prefix of a longer
+// entity has to stay in holdback until it's resolved one way or another.
+const slidingWindow = 64
+
+// maxPendingBuffer forces a flush even without a sentence/line boundary,
+// so a client that never sends punctuation can't grow the buffer
+// unbounded.
+const maxPendingBuffer = 4096
+
+var wsUpgrader = websocket.Upgrader{
+	// Mirrors the permissive CORS policy used for the REST endpoints.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsChunkFrame is one incoming frame from the client.
+type wsChunkFrame struct {
+	Chunk string `json:"chunk"`
+	Done  bool   `json:"done"`
+}
+
+// wsDeltaFrame is one outgoing frame: the newly anonymized text plus the
+// entities found in it, and any encrypt-operator manifest entries
+// produced while rendering them.
+type wsDeltaFrame struct {
+	Delta    string            `json:"delta"`
+	Entities []Entity          `json:"entities,omitempty"`
+	Manifest map[string]string `json:"manifest,omitempty"`
+	Done     bool              `json:"done,omitempty"`
+}
+
+// streamBuffer implements the flush-on-boundary state machine for one
+// WebSocket connection. It is not safe for concurrent use.
+type streamBuffer struct {
+	pending  strings.Builder
+	holdback string // trailing text not yet committed to the client, see slidingWindow
+	consumed int    // runes already committed to the client, for absolute entity offsets
+	entities []Entity
+}
+
+// feed appends a chunk and returns the text ready to flush, if a
+// sentence/line boundary (or the size cap) was reached.
+func (b *streamBuffer) feed(chunk string) (toFlush string, ready bool) {
+	b.pending.WriteString(chunk)
+	text := b.pending.String()
+
+	boundary := lastBoundary(text)
+	if boundary == -1 && len(text) < maxPendingBuffer {
+		return "", false
+	}
+	if boundary == -1 {
+		boundary = len(text)
+	}
+
+	toFlush = text[:boundary]
+	b.pending.Reset()
+	b.pending.WriteString(text[boundary:])
+	return toFlush, true
+}
+
+// rest returns whatever is left in the buffer once the stream ends.
+func (b *streamBuffer) rest() string {
+	return b.pending.String()
+}
+
+// lastBoundary finds the end of the last complete sentence or line in
+// text, i.e. the index just past a '.', '!', '?' or '\n' that is
+// followed by whitespace or end-of-string. Returns -1 if none found.
+func lastBoundary(text string) int {
+	best := -1
+	for i, r := range text {
+		if r != '.' && r != '!' && r != '?' && r != '\n' {
+			continue
+		}
+		end := i + 1
+		if end == len(text) || text[end] == ' ' || text[end] == '\n' || text[end] == '\t' {
+			best = end
+		}
+	}
+	return best
+}
+
+// HandleWebSocketAnonymize upgrades to a WebSocket and incrementally
+// anonymizes incoming text chunks, flushing complete sentences/lines as
+// they arrive instead of waiting for the full document.
+func (h *ProxyHandler) HandleWebSocketAnonymize(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("websocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	buf := &streamBuffer{}
+	for {
+		var frame wsChunkFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+
+		if frame.Chunk != "" {
+			if toFlush, ready := buf.feed(frame.Chunk); ready {
+				if err := h.flushChunk(conn, buf, toFlush, false); err != nil {
+					log.Printf("websocket flush failed: %v", err)
+					return
+				}
+			}
+		}
+
+		if frame.Done {
+			if err := h.flushChunk(conn, buf, buf.rest(), true); err != nil {
+				log.Printf("websocket flush failed: %v", err)
+				return
+			}
+			_ = writeWSFrame(conn, wsDeltaFrame{Done: true, Entities: buf.entities})
+			return
+		}
+	}
+}
+
+// windowPiece is one span of the holdback+text window: either a
+// literal run of text (rendered verbatim) or a detected entity (rendered
+// through the configured operator, which may change its length). Start
+// and End are rune offsets into the window, not bytes — Presidio's
+// entity offsets are codepoint offsets, and slicing by byte would shift
+// everything after the first multi-byte rune (the chunk0-3 bug).
+type windowPiece struct {
+	entity bool
+	start  int
+	end    int
+	text   string // only set for literal pieces
+	ent    Entity // only set for entity pieces
+}
+
+// sortedValidEntities drops any entity whose span doesn't fit within
+// runes and returns the rest sorted ascending by Start.
+func sortedValidEntities(runes []rune, entities []Entity) []Entity {
+	sorted := make([]Entity, 0, len(entities))
+	for _, e := range entities {
+		if e.Start >= 0 && e.End <= len(runes) && e.Start <= e.End {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+// buildWindowPieces splits runes into an ordered, non-overlapping set of
+// pieces covering [0, len(runes)): a literal piece for every gap between
+// entities, and an entity piece for each detected span. Out-of-range or
+// malformed spans are dropped rather than panicking, same as
+// OperatorEngine.Apply.
+func buildWindowPieces(runes []rune, entities []Entity) []windowPiece {
+	sorted := sortedValidEntities(runes, entities)
+
+	var pieces []windowPiece
+	pos := 0
+	for _, e := range sorted {
+		if e.Start < pos {
+			continue // overlaps the previous span, skip rather than double-render
+		}
+		if e.Start > pos {
+			pieces = append(pieces, windowPiece{start: pos, end: e.Start, text: string(runes[pos:e.Start])})
+		}
+		pieces = append(pieces, windowPiece{entity: true, start: e.Start, end: e.End, ent: e})
+		pos = e.End
+	}
+	if pos < len(runes) {
+		pieces = append(pieces, windowPiece{start: pos, end: len(runes), text: string(runes[pos:len(runes)])})
+	}
+	return pieces
+}
+
+// commitEnd picks how many runes of windowed (final count len(runes)) are
+// safe to commit to the client this flush. On a non-final flush it holds
+// back the trailing slidingWindow runes so a PII span that isn't
+// finished yet never gets written out; on the final flush nothing more
+// is coming, so everything is committed. Either way, if any detected
+// entity straddles the chosen cutoff, the cutoff is pulled back to that
+// entity's start — an entity can only be committed once it's entirely
+// within the committed region, since its operator output (e.g. a hash
+// token) isn't something a partial commit could later amend.
+func commitEnd(runes []rune, entities []Entity, final bool) int {
+	end := len(runes)
+	if !final && end > slidingWindow {
+		end -= slidingWindow
+	} else if !final {
+		end = 0
+	}
+
+	for _, p := range buildWindowPieces(runes, entities) {
+		if p.entity && p.start < end && p.end > end {
+			end = p.start // non-overlapping + ascending, so this is the only match
+		}
+	}
+	return end
+}
+
+// flushChunk re-analyzes holdback+text, anonymizes the portion that's
+// now safe to commit through the configured operators, and writes it as
+// a delta frame. Whatever isn't committed (the trailing holdback window,
+// or an entity still straddling it) is kept verbatim in buf.holdback and
+// re-analyzed together with the next chunk, so a span split across two
+// chunks is fully resolved before any of it reaches the client.
+func (h *ProxyHandler) flushChunk(conn *websocket.Conn, buf *streamBuffer, text string, final bool) error {
+	windowed := buf.holdback + text
+	if windowed == "" {
+		return nil
+	}
+	runes := []rune(windowed)
+
+	entities, err := h.presidio.Analyze(windowed)
+	if err != nil {
+		return err
+	}
+
+	end := commitEnd(runes, entities, final)
+
+	var deltaText strings.Builder
+	var fresh []Entity
+	manifest := map[string]string{}
+	for _, p := range buildWindowPieces(runes, entities) {
+		if p.start >= end {
+			break // not yet committed; stays in holdback for next time
+		}
+
+		if !p.entity {
+			// commitEnd guarantees no entity straddles end, but a literal
+			// run can, so it's trimmed to its committed prefix here.
+			stop := p.end
+			if stop > end {
+				stop = end
+			}
+			deltaText.WriteString(string(runes[p.start:stop]))
+			continue
+		}
+
+		original := string(runes[p.start:p.end])
+		op := h.config.GetOperator(p.ent.EntityType)
+		rendered, err := h.operators.render(p.ent.EntityType, original, op, manifest)
+		if err != nil {
+			return err
+		}
+		deltaText.WriteString(rendered)
+
+		e := p.ent
+		e.Start += buf.consumed
+		e.End += buf.consumed
+		fresh = append(fresh, e)
+	}
+
+	buf.entities = append(buf.entities, fresh...)
+	buf.consumed += end
+	buf.holdback = string(runes[end:])
+
+	return writeWSFrame(conn, wsDeltaFrame{Delta: deltaText.String(), Entities: fresh, Manifest: manifest})
+}
+
+// writeWSFrame writes v as a JSON text frame without HTML escaping, same
+// as writeJSON, so placeholders like <PERSON> survive intact.
+func writeWSFrame(conn *websocket.Conn, v any) error {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, []byte(buf.String()))
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+// TestCommitEndHoldsBackStraddlingEntity guards against the regression
+// where a PII span straddling the commit boundary was written to the
+// client either partially or before it was fully resolved.
+func TestCommitEndHoldsBackStraddlingEntity(t *testing.T) {
+	// 87 runes: the 64-rune holdback puts the cutoff at 23, inside the span.
+	text := "Please contact John F. Kennedy for more details about the upcoming conference schedule."
+	runes := []rune(text)
+	entities := []Entity{{EntityType: "PERSON", Start: 15, End: 30}} // "John F. Kennedy"
+
+	end := commitEnd(runes, entities, false)
+	if end > 15 {
+		t.Fatalf("commitEnd = %d, want <= 15 (the entity's start)", end)
+	}
+
+	for _, p := range buildWindowPieces(runes, entities) {
+		if p.start >= end {
+			break
+		}
+		if p.entity && p.end > end {
+			t.Fatal("a straddling entity must never be committed")
+		}
+	}
+}
+
+// TestCommitEndFinalFlushCommitsEverything checks that the last flush of
+// a stream (no more chunks coming) never holds anything back.
+func TestCommitEndFinalFlushCommitsEverything(t *testing.T) {
+	runes := []rune("John F. Kennedy")
+	entities := []Entity{{EntityType: "PERSON", Start: 0, End: len(runes)}}
+
+	if end := commitEnd(runes, entities, true); end != len(runes) {
+		t.Fatalf("commitEnd(final) = %d, want %d", end, len(runes))
+	}
+}
+
+// TestBuildWindowPiecesCoversWholeWindow checks that the literal and
+// entity pieces returned by buildWindowPieces tile [0, len(runes))
+// exactly, with no gaps or overlaps.
+func TestBuildWindowPiecesCoversWholeWindow(t *testing.T) {
+	text := "Hi José, email alice@example.com today"
+	runes := []rune(text)
+	entities := []Entity{{EntityType: "EMAIL", Start: 15, End: 32}} // "alice@example.com", in runes
+
+	pos := 0
+	for _, p := range buildWindowPieces(runes, entities) {
+		if p.start != pos {
+			t.Fatalf("piece start %d != expected %d", p.start, pos)
+		}
+		pos = p.end
+	}
+	if pos != len(runes) {
+		t.Fatalf("pieces covered up to %d, want %d", pos, len(runes))
+	}
+}